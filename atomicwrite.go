@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// atomicWriteFile writes data to path without ever leaving a truncated or
+// corrupt file behind if the process dies mid-write: it writes to
+// path+".tmp" in the same directory, fsyncs it, then renames it over path.
+// The rename is atomic on the same filesystem, so readers only ever see
+// the old complete file or the new complete file, never a partial one.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", tmpPath, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("error writing %s: %w", tmpPath, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("error syncing %s: %w", tmpPath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("error renaming %s to %s: %w", tmpPath, path, err)
+	}
+	return nil
+}
+
+// backupPath returns the rollback copy atomicSaveWithBackup keeps
+// alongside path.
+func backupPath(path string) string {
+	return path + ".bak"
+}
+
+// atomicSaveWithBackup atomically writes data to path, but first preserves
+// whatever was previously at path as path+".bak" so a later rollback can
+// recover it. If path doesn't exist yet, no backup is written.
+func atomicSaveWithBackup(path string, data []byte, perm os.FileMode) error {
+	if old, err := os.ReadFile(path); err == nil {
+		if err := atomicWriteFile(backupPath(path), old, perm); err != nil {
+			return fmt.Errorf("error backing up %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error reading %s for backup: %w", path, err)
+	}
+	return atomicWriteFile(path, data, perm)
+}
+
+// rollbackFromBackup restores path from its path+".bak" copy, written by a
+// prior atomicSaveWithBackup call. It returns an error if no backup exists.
+func rollbackFromBackup(path string, perm os.FileMode) error {
+	data, err := os.ReadFile(backupPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no backup found for %s", filepath.Base(path))
+		}
+		return fmt.Errorf("error reading backup of %s: %w", path, err)
+	}
+	return atomicWriteFile(path, data, perm)
+}
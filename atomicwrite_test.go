@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteFileWritesAndLeavesNoTmpFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	if err := atomicWriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("atomicWriteFile() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("content = %q, want %q", data, "hello")
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected %s.tmp to be gone after rename, stat err = %v", path, err)
+	}
+}
+
+func TestAtomicSaveWithBackupPreservesPriorContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prev.json")
+
+	if err := atomicSaveWithBackup(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("atomicSaveWithBackup(first) error = %v", err)
+	}
+	if _, err := os.Stat(backupPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup after the first save, stat err = %v", err)
+	}
+
+	if err := atomicSaveWithBackup(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("atomicSaveWithBackup(second) error = %v", err)
+	}
+	backup, err := os.ReadFile(backupPath(path))
+	if err != nil {
+		t.Fatalf("error reading backup: %v", err)
+	}
+	if string(backup) != "first" {
+		t.Errorf("backup content = %q, want %q", backup, "first")
+	}
+	curr, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+	if string(curr) != "second" {
+		t.Errorf("current content = %q, want %q", curr, "second")
+	}
+}
+
+func TestRollbackFromBackupRestoresPriorContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prev.json")
+	if err := atomicSaveWithBackup(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("atomicSaveWithBackup(first) error = %v", err)
+	}
+	if err := atomicSaveWithBackup(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("atomicSaveWithBackup(second) error = %v", err)
+	}
+	if err := rollbackFromBackup(path, 0644); err != nil {
+		t.Fatalf("rollbackFromBackup() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error reading %s: %v", path, err)
+	}
+	if string(got) != "first" {
+		t.Errorf("content after rollback = %q, want %q", got, "first")
+	}
+}
+
+func TestRollbackFromBackupErrorsWithoutBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prev.json")
+	if err := rollbackFromBackup(path, 0644); err == nil {
+		t.Fatalf("expected an error rolling back with no backup present")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+// ChangeKind identifies the kind of change a Change describes.
+type ChangeKind string
+
+const (
+	Added    ChangeKind = "added"
+	Modified ChangeKind = "modified"
+	Deleted  ChangeKind = "deleted"
+	Renamed  ChangeKind = "renamed"
+)
+
+// Change is a single structured diff event between two FileHashes snapshots.
+// OldPath is only set for Renamed changes.
+type Change struct {
+	Kind    ChangeKind `json:"kind"`
+	Path    string     `json:"path"`
+	OldPath string     `json:"old_path,omitempty"`
+	Hash    string     `json:"hash,omitempty"`
+}
+
+// diffChanges compares prevHashes against currHashes and returns a Change
+// per added, modified, deleted, or renamed path. A deleted path and an added
+// path that share the same hash are reported as a single Renamed change
+// rather than as a Deleted/Added pair.
+func diffChanges(prevHashes, currHashes FileHashes) []Change {
+	added := make(map[string]FileEntry)
+	deleted := make(map[string]FileEntry)
+	var changes []Change
+
+	for path, entry := range currHashes {
+		prevEntry, ok := prevHashes[path]
+		if !ok {
+			added[path] = entry
+			continue
+		}
+		if prevEntry.Hash != entry.Hash {
+			changes = append(changes, Change{Kind: Modified, Path: path, Hash: entry.Hash})
+		}
+	}
+	for path, entry := range prevHashes {
+		if _, ok := currHashes[path]; !ok {
+			deleted[path] = entry
+		}
+	}
+
+	changes = append(changes, pairRenames(added, deleted)...)
+	return changes
+}
+
+// pairRenames matches added against deleted by hash, reporting each match as
+// a single Renamed change and consuming both sides of the match in the
+// process; whatever's left over is reported as a plain Added or Deleted
+// change. Shared by diffChanges and the watch daemon's per-flush batches so
+// both report the same rename the same way.
+func pairRenames(added, deleted map[string]FileEntry) []Change {
+	var changes []Change
+	for oldPath, oldEntry := range deleted {
+		matched := false
+		for newPath, newEntry := range added {
+			if newEntry.Hash == oldEntry.Hash {
+				changes = append(changes, Change{Kind: Renamed, Path: newPath, OldPath: oldPath, Hash: newEntry.Hash})
+				delete(added, newPath)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			changes = append(changes, Change{Kind: Deleted, Path: oldPath, Hash: oldEntry.Hash})
+		}
+	}
+	for path, entry := range added {
+		changes = append(changes, Change{Kind: Added, Path: path, Hash: entry.Hash})
+	}
+	return changes
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"sort"
+	"testing"
+)
+
+func sortedChanges(changes []Change) []Change {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func TestDiffChangesAddedModifiedDeleted(t *testing.T) {
+	prev := FileHashes{
+		"a.txt": {Hash: "sha256:aaa"},
+		"b.txt": {Hash: "sha256:bbb"},
+	}
+	curr := FileHashes{
+		"a.txt": {Hash: "sha256:aaa2"},
+		"c.txt": {Hash: "sha256:ccc"},
+	}
+
+	got := sortedChanges(diffChanges(prev, curr))
+	want := []Change{
+		{Kind: Added, Path: "c.txt", Hash: "sha256:ccc"},
+		{Kind: Deleted, Path: "b.txt", Hash: "sha256:bbb"},
+		{Kind: Modified, Path: "a.txt", Hash: "sha256:aaa2"},
+	}
+	want = sortedChanges(want)
+	if len(got) != len(want) {
+		t.Fatalf("diffChanges() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiffChangesDetectsRename(t *testing.T) {
+	prev := FileHashes{
+		"old/path.txt": {Hash: "sha256:same"},
+	}
+	curr := FileHashes{
+		"new/path.txt": {Hash: "sha256:same"},
+	}
+
+	got := diffChanges(prev, curr)
+	if len(got) != 1 {
+		t.Fatalf("diffChanges() = %+v, want exactly one Renamed change", got)
+	}
+	want := Change{Kind: Renamed, Path: "new/path.txt", OldPath: "old/path.txt", Hash: "sha256:same"}
+	if got[0] != want {
+		t.Errorf("diffChanges()[0] = %+v, want %+v", got[0], want)
+	}
+}
+
+func TestDiffChangesDoesNotRenameAcrossDifferentHashes(t *testing.T) {
+	prev := FileHashes{
+		"old.txt": {Hash: "sha256:aaa"},
+	}
+	curr := FileHashes{
+		"new.txt": {Hash: "sha256:bbb"},
+	}
+
+	got := sortedChanges(diffChanges(prev, curr))
+	want := sortedChanges([]Change{
+		{Kind: Added, Path: "new.txt", Hash: "sha256:bbb"},
+		{Kind: Deleted, Path: "old.txt", Hash: "sha256:aaa"},
+	})
+	if len(got) != len(want) {
+		t.Fatalf("diffChanges() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
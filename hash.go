@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// defaultHashAlgorithm is used when --hash isn't passed and no prior
+// snapshot records one.
+const defaultHashAlgorithm = "sha256"
+
+// Hasher names a hash algorithm and constructs instances of it. Digests
+// produced from it are stored as self-describing multihash-style strings
+// ("<name>:<hex>"), so a tree can mix digests from different algorithms
+// across runs and still tell them apart.
+type Hasher interface {
+	Name() string
+	New() hash.Hash
+}
+
+type sha256Hasher struct{}
+
+func (sha256Hasher) Name() string   { return "sha256" }
+func (sha256Hasher) New() hash.Hash { return sha256.New() }
+
+type sha512Hasher struct{}
+
+func (sha512Hasher) Name() string   { return "sha512" }
+func (sha512Hasher) New() hash.Hash { return sha512.New() }
+
+var hashers = map[string]Hasher{
+	"sha256": sha256Hasher{},
+	"sha512": sha512Hasher{},
+}
+
+// hasherFor looks up a Hasher by name, as passed to --hash or read back
+// from a snapshot's Algorithm field.
+func hasherFor(name string) (Hasher, error) {
+	h, ok := hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q (supported: sha256, sha512)", name)
+	}
+	return h, nil
+}
+
+// formatDigest renders a digest as a self-describing multihash-style
+// string, e.g. "sha256:1f2e...".
+func formatDigest(algorithm string, sum []byte) string {
+	return fmt.Sprintf("%s:%x", algorithm, sum)
+}
+
+// digestAlgorithm extracts the algorithm name from a digest previously
+// produced by formatDigest, defaulting to defaultHashAlgorithm for digests
+// stored before this self-describing format existed.
+func digestAlgorithm(digest string) string {
+	if algo, _, ok := strings.Cut(digest, ":"); ok {
+		return algo
+	}
+	return defaultHashAlgorithm
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDigestAlgorithm(t *testing.T) {
+	cases := map[string]string{
+		"sha256:deadbeef": "sha256",
+		"sha512:deadbeef": "sha512",
+		"deadbeef":        defaultHashAlgorithm,
+	}
+	for digest, want := range cases {
+		if got := digestAlgorithm(digest); got != want {
+			t.Errorf("digestAlgorithm(%q) = %q, want %q", digest, got, want)
+		}
+	}
+}
+
+// TestFileEntryUnchangedRehashesOnAlgorithmChange ensures a cached entry
+// produced under one hash algorithm is treated as stale once --hash picks a
+// different one, so switching algorithms migrates digests instead of
+// silently keeping the old ones around forever.
+func TestFileEntryUnchangedRehashesOnAlgorithmChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("error stating file: %v", err)
+	}
+
+	entry := FileEntry{Hash: "sha256:deadbeef", ModTime: info.ModTime(), Size: info.Size()}
+
+	sha256Hasher, err := hasherFor("sha256")
+	if err != nil {
+		t.Fatalf("error resolving sha256 hasher: %v", err)
+	}
+	if !entry.unchanged(info, sha256Hasher) {
+		t.Fatalf("expected entry to be unchanged against the algorithm it was hashed with")
+	}
+
+	sha512Hasher, err := hasherFor("sha512")
+	if err != nil {
+		t.Fatalf("error resolving sha512 hasher: %v", err)
+	}
+	if entry.unchanged(info, sha512Hasher) {
+		t.Fatalf("expected entry hashed with sha256 to be stale once the active hasher switches to sha512")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFilePath is the location of the user-supplied, gitignore-style
+// exclusion patterns relative to the directory being watched.
+const ignoreFilePath = "watcher/.watchignore"
+
+// IgnoreList holds the glob patterns loaded from .watchignore.
+type IgnoreList struct {
+	patterns []string
+}
+
+// loadIgnoreList reads watcher/.watchignore if it exists. Blank lines and
+// lines starting with '#' are skipped, mirroring .gitignore conventions.
+func loadIgnoreList() (*IgnoreList, error) {
+	f, err := os.Open(ignoreFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &IgnoreList{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	il := &IgnoreList{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		il.patterns = append(il.patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return il, nil
+}
+
+// Matches reports whether the given slash-separated relative path should be
+// excluded. A trailing '/' on a pattern restricts the match to directories.
+// Patterns without a path separator are matched against the base name as
+// well as the full path, the way git matches a plain "*.log" pattern
+// anywhere in the tree.
+func (il *IgnoreList) Matches(relPath string, isDir bool) bool {
+	if il == nil {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+	base := filepath.Base(relPath)
+	for _, pattern := range il.patterns {
+		p := pattern
+		dirOnly := strings.HasSuffix(p, "/")
+		if dirOnly {
+			p = strings.TrimSuffix(p, "/")
+			if !isDir {
+				continue
+			}
+		}
+		if ok, _ := filepath.Match(p, relPath); ok {
+			return true
+		}
+		if !strings.Contains(p, "/") {
+			if ok, _ := filepath.Match(p, base); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
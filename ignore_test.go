@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestIgnoreListMatches(t *testing.T) {
+	il := &IgnoreList{patterns: []string{
+		"*.log",
+		"build/",
+		"vendor/secret.txt",
+	}}
+
+	cases := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"debug.log", false, true},
+		{"nested/debug.log", false, true},
+		{"build", true, true},
+		{"build/output.bin", false, false},
+		{"vendor/secret.txt", false, true},
+		{"vendor/other.txt", false, false},
+		{"notes.txt", false, false},
+	}
+	for _, c := range cases {
+		if got := il.Matches(c.path, c.isDir); got != c.want {
+			t.Errorf("Matches(%q, isDir=%v) = %v, want %v", c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestIgnoreListMatchesNilReceiver(t *testing.T) {
+	var il *IgnoreList
+	if il.Matches("anything.log", false) {
+		t.Fatalf("expected a nil IgnoreList to match nothing")
+	}
+}
@@ -1,21 +1,49 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
-const prevFilePath = "watcher/prev.json"
-const changedFilesPath = "watcher/changed_files.txt"
+const watcherDirName = "watcher"
+const prevFilePath = watcherDirName + "/prev.json"
+const changedFilesPath = watcherDirName + "/changed_files.txt"
+const changesReportPath = watcherDirName + "/changes.json"
 
-// FileHashes stores filenames and their hashes.
-type FileHashes map[string]string
+// FileEntry caches everything needed to decide whether a file needs
+// rehashing, plus the hash itself once it's known.
+type FileEntry struct {
+	Hash    string    `json:"hash"`
+	ModTime time.Time `json:"mod_time"`
+	Size    int64     `json:"size"`
+}
+
+// FileHashes stores filenames and their cached hash entries.
+type FileHashes map[string]FileEntry
+
+// Snapshot is the full contents of a persisted prev.json: the file entries
+// plus the hash algorithm they were produced with, so a later run can tell
+// which algorithm to keep using (or that it needs to migrate) even after
+// the tool's default changes.
+type Snapshot struct {
+	Algorithm string     `json:"algorithm"`
+	Files     FileHashes `json:"files"`
+}
+
+// unchanged reports whether entry still matches the given stat info and was
+// produced by hasher, meaning it can be reused without rehashing the file's
+// contents. A snapshot read back after --hash switched algorithms no longer
+// matches, so the file is rehashed and migrated to the new digest format.
+func (e FileEntry) unchanged(info fs.FileInfo, hasher Hasher) bool {
+	return e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) && digestAlgorithm(e.Hash) == hasher.Name()
+}
 
 func main() {
 	if len(os.Args) < 2 {
@@ -34,159 +62,315 @@ func main() {
 		printHelpMenu()
 		return
 	} else if os.Args[1] == "clear" {
-		err := clearPrevJson()
+		rolledBack, err := clearPrevJson()
 		if err != nil {
 			fmt.Printf("Error cleaning 'prev.json': %s\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("'prev.json' cleared successfully!")
+		if rolledBack {
+			fmt.Println("'prev.json' rolled back to its previous snapshot.")
+		} else {
+			fmt.Println("'prev.json' cleared successfully!")
+		}
 		return
 	}
 	path := os.Args[1]
-	prevHashes := loadPrevHashes()
-	currHashes := computeHashes(path)
-	latestChanges := make(FileHashes)
-	for file, _ := range currHashes {
-		if _, ok := prevHashes[file]; ok || len(prevHashes) == 0 {
-			if prevHashes[file] != currHashes[file] {
-				latestChanges[file] = currHashes[file]
-			}
-		} else {
-			latestChanges[file] = currHashes[file]
+	followSymlinks := false
+	watchMode := false
+	listenAddr := ""
+	store := Store(NewLocalStore(prevFilePath))
+	hashAlgorithm := ""
+	for _, arg := range os.Args[2:] {
+		if arg == "--follow-symlinks" {
+			followSymlinks = true
+		} else if arg == "watch" {
+			watchMode = true
+		} else if rest, ok := strings.CutPrefix(arg, "--store-url="); ok {
+			store = NewHTTPStore(rest)
+		} else if rest, ok := strings.CutPrefix(arg, "--hash="); ok {
+			hashAlgorithm = rest
+		} else if rest, ok := strings.CutPrefix(arg, "--listen="); ok {
+			listenAddr = rest
 		}
 	}
-	err := saveHashes(currHashes, latestChanges)
+	prevSnapshot, err := store.Load()
 	if err != nil {
+		log.Fatalf("Error loading previous snapshot: %v", err)
+	}
+	if hashAlgorithm == "" {
+		hashAlgorithm = prevSnapshot.Algorithm
+	}
+	if hashAlgorithm == "" {
+		hashAlgorithm = defaultHashAlgorithm
+	}
+	hasher, err := hasherFor(hashAlgorithm)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	prevHashes := prevSnapshot.Files
+
+	if watchMode {
+		ignoreList, err := loadIgnoreList()
+		if err != nil {
+			log.Fatalf("Error reading .watchignore: %v", err)
+		}
+		if err := runWatch(path, prevHashes, followSymlinks, hasher, hashAlgorithm, store, ignoreList, listenAddr); err != nil {
+			log.Fatalf("Error watching %s: %v", path, err)
+		}
+		return
+	}
+
+	currHashes := computeHashes(path, prevHashes, followSymlinks, hasher)
+	changes := diffChanges(prevHashes, currHashes)
+	currSnapshot := &Snapshot{Algorithm: hashAlgorithm, Files: currHashes}
+	if err := saveHashes(store, currSnapshot, changes); err != nil {
 		// No changes
-		writeTxtFile(latestChanges) // need to make sure the txt file is empty
+		writeChangeReports(changes) // need to make sure the report files are empty
 		fmt.Println(err)
 	} else {
 		fmt.Println("Changed files:")
-		for file, _ := range latestChanges {
-			fmt.Println(file)
+		for _, change := range changes {
+			fmt.Printf("%s: %s\n", change.Kind, change.Path)
 		}
-		fmt.Println("\nFiles written to 'watcher/changed_files.txt'.")
+		fmt.Println("\nDetails written to 'watcher/changed_files.txt' and 'watcher/changes.json'.")
 	}
 }
 
-// computeHashes computes SHA-256 hashes for the files in the given directory.
-func computeHashes(dir string) FileHashes {
+// computeHashes walks dir recursively, skipping anything matched by
+// watcher/.watchignore. Entries already present in prevHashes whose size
+// and mtime haven't changed are reused as-is; everything else is handed off
+// to a worker pool (see hashFilesParallel) so large trees don't hash files
+// one at a time. followSymlinks controls whether symlinked directories are
+// traversed (with cycle detection via the real path) or left alone.
+func computeHashes(dir string, prevHashes FileHashes, followSymlinks bool, hasher Hasher) FileHashes {
 	hashes := make(FileHashes)
-	entries, err := os.ReadDir(dir)
+	var pending []string
+	ignoreList, err := loadIgnoreList()
 	if err != nil {
+		log.Fatalf("Error reading .watchignore: %v", err)
+	}
+	visitedDirs := make(map[string]bool)
+
+	// walk traverses walkRoot on disk but reports every path as if it were
+	// rooted at apparentRoot instead. For a plain scan the two are the same
+	// directory; when handleSymlink recurses into a symlinked directory,
+	// walkRoot is the resolved real path (so WalkDir sees an ordinary
+	// directory, not the symlink itself, on its very first entry) while
+	// apparentRoot stays the symlink's own path, so reported entries still
+	// live under the tree the caller asked to watch.
+	var walk func(walkRoot, apparentRoot string) error
+	walk = func(walkRoot, apparentRoot string) error {
+		return filepath.WalkDir(walkRoot, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			apparent := apparentRoot + strings.TrimPrefix(p, walkRoot)
+			rel, relErr := filepath.Rel(dir, apparent)
+			if relErr != nil {
+				rel = apparent
+			}
+			if d.IsDir() {
+				if apparent != dir && (rel == watcherDirName || ignoreList.Matches(rel, true)) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type()&fs.ModeSymlink != 0 {
+				return handleSymlink(apparent, rel, dir, followSymlinks, visitedDirs, ignoreList, hashes, &pending, prevHashes, hasher, walk)
+			}
+			if ignoreList.Matches(rel, false) {
+				return nil
+			}
+			collectPath(apparent, prevHashes, hashes, &pending, hasher)
+			return nil
+		})
+	}
+	if err := walk(dir, dir); err != nil {
 		log.Fatalf("Error reading directory: %v", err)
 	}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		path := filepath.Join(dir, entry.Name())
-		hash, err := hashFile(path)
-		if err != nil {
-			log.Printf("Error hashing file %s: %v", path, err)
-			continue
-		}
-		hashes[path] = hash
+	for path, entry := range hashFilesParallel(pending, hasher) {
+		hashes[path] = entry
 	}
 	return hashes
 }
 
-// hashFile computes the SHA-256 hash of a file's contents.
-func hashFile(filename string) (string, error) {
-	f, err := os.Open(filename)
+// handleSymlink resolves a symlink encountered during the walk. When
+// followSymlinks is false the link is skipped entirely. When true, it's
+// resolved and, if it points at a directory, recursed into guarded by
+// visitedDirs (keyed on the real path) to avoid infinite cycles. The
+// recursion walks the resolved real directory but keeps reporting paths
+// under path (the symlink), so WalkDir's first entry is the real directory
+// itself rather than the symlink again.
+func handleSymlink(path, rel, root string, followSymlinks bool, visitedDirs map[string]bool, ignoreList *IgnoreList, hashes FileHashes, pending *[]string, prevHashes FileHashes, hasher Hasher, walk func(string, string) error) error {
+	if !followSymlinks {
+		return nil
+	}
+	if ignoreList.Matches(rel, false) {
+		return nil
+	}
+	real, err := filepath.EvalSymlinks(path)
 	if err != nil {
-		return "", fmt.Errorf("error opening file %s: %w", filename, err)
+		log.Printf("Error resolving symlink %s: %v", path, err)
+		return nil
 	}
-	defer f.Close()
+	info, err := os.Stat(real)
+	if err != nil {
+		log.Printf("Error stating symlink target %s: %v", path, err)
+		return nil
+	}
+	if info.IsDir() {
+		if visitedDirs[real] {
+			return nil
+		}
+		visitedDirs[real] = true
+		return walk(real, path)
+	}
+	collectPath(path, prevHashes, hashes, pending, hasher)
+	return nil
+}
 
-	hasher := sha256.New()
-	if _, err := io.Copy(hasher, f); err != nil {
-		return "", fmt.Errorf("error hashing file %s: %w", filename, err)
+// collectPath stats path and either reuses the cached hash from prevHashes
+// (writing it straight into hashes) or queues path onto pending for the
+// worker pool to hash later. A cached entry produced with a different
+// hasher than the current one is treated as stale so it gets rehashed and
+// migrated to the active algorithm.
+func collectPath(path string, prevHashes, hashes FileHashes, pending *[]string, hasher Hasher) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("Error stating file %s: %v", path, err)
+		return
+	}
+	if prev, ok := prevHashes[path]; ok && prev.unchanged(info, hasher) {
+		hashes[path] = prev
+		return
 	}
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	*pending = append(*pending, path)
 }
 
-func loadPrevHashes() FileHashes {
-	data, err := os.ReadFile(prevFilePath)
+// hashFile computes a self-describing digest ("<algorithm>:<hex>") of a
+// file's contents using hasher.
+func hashFile(filename string, hasher Hasher) (string, error) {
+	f, err := os.Open(filename)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return make(FileHashes) // If the file doesn't exist, return an empty map.
-		}
-		log.Fatalf("Error reading hash file: %v", err)
+		return "", fmt.Errorf("error opening file %s: %w", filename, err)
 	}
-	var hashes FileHashes
-	if err := json.Unmarshal(data, &hashes); err != nil {
-		log.Fatalf("Error unmarshaling hash file: %v", err)
+	defer f.Close()
+
+	h := hasher.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error hashing file %s: %w", filename, err)
 	}
-	return hashes
+	return formatDigest(hasher.Name(), h.Sum(nil)), nil
 }
 
-func saveHashes(currHashes, changedHashes FileHashes) error {
-	if len(changedHashes) == 0 {
-		// Avoid resetting the JSON if no changes occurred.
+func saveHashes(store Store, currSnapshot *Snapshot, changes []Change) error {
+	if len(changes) == 0 {
+		// Avoid resetting the reports if no changes occurred.
 		return fmt.Errorf("No changes detected. 'changed_files.txt' cleared.")
 	}
-	currData, err := json.MarshalIndent(currHashes, "", "  ")
-	if err != nil {
-		log.Fatalf("Error marshaling current hashes: %v", err)
-	}
-	if err := os.WriteFile(prevFilePath, currData, 0644); err != nil {
-		fmt.Printf("Error: %s.\nPlease run `watcher init` to generate necessary files.\n", err)
+	if err := store.Save(currSnapshot); err != nil {
+		fmt.Printf("Error: %s\n", err)
 		os.Exit(1)
 	}
-	writeTxtFile(changedHashes)
+	writeChangeReports(changes)
 	return nil
 }
 
-func writeTxtFile(hashes FileHashes) {
+// writeChangeReports writes the flat, human-readable changed_files.txt
+// alongside the structured changes.json report, so downstream tools can
+// consume typed events instead of parsing a filename list.
+func writeChangeReports(changes []Change) {
 	contents := ""
-	for file, _ := range hashes {
-		contents += file + "\n"
+	for _, change := range changes {
+		contents += change.Path + "\n"
 	}
-	if err := os.WriteFile(changedFilesPath, []byte(contents), 0644); err != nil {
+	if err := atomicWriteFile(changedFilesPath, []byte(contents), 0644); err != nil {
 		log.Fatalf("Error writing text file: %v\n", err)
 	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	report, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		log.Fatalf("Error marshaling changes report: %v\n", err)
+	}
+	if err := atomicWriteFile(changesReportPath, report, 0644); err != nil {
+		log.Fatalf("Error writing changes report: %v\n", err)
+	}
 }
 
 func initWatcher() error {
 	os.Mkdir("watcher", 0755)
-	f, err := os.OpenFile("watcher/prev.json", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err := NewLocalStore(prevFilePath).Save(&Snapshot{Algorithm: defaultHashAlgorithm, Files: make(FileHashes)}); err != nil {
+		return err
+	}
+	f2, err := os.OpenFile("watcher/changed_files.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	_, err = f.Write([]byte("{}"))
+	_, err = f2.Write([]byte(""))
 	if err != nil {
 		return err
 	}
-	f.Close()
-	f2, err := os.OpenFile("watcher/changed_files.txt", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f2.Close()
+	f3, err := os.OpenFile(ignoreFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	_, err = f2.Write([]byte(""))
+	_, err = f3.Write([]byte("# One gitignore-style glob pattern per line.\n"))
 	if err != nil {
 		return err
 	}
-	f2.Close()
+	f3.Close()
+	f4, err := os.OpenFile(changesReportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f4.Write([]byte("[]"))
+	if err != nil {
+		return err
+	}
+	f4.Close()
 	return nil
 }
 
 func printHelpMenu() {
 	fmt.Println(
 		"Welcome to the file watcher help menu!\n\n" +
-		"Usage: watcher <directory_path> <opt_command>\n\n" +
-		"Valid commands:\n" +
-		"help  - Shows this menu.\n" + 
-		"init  - Generate the necessary directory structure for the tool.\n" +
-		"clear - Clears 'prev.json' in case it gets corrupted.\n" + 
-		"        Running the tool again will repopulate it.",
+			"Usage: watcher <directory_path> [--follow-symlinks] <opt_command>\n\n" +
+			"Valid commands:\n" +
+			"help  - Shows this menu.\n" +
+			"init  - Generate the necessary directory structure for the tool.\n" +
+			"clear - Rolls 'prev.json' back to the snapshot it held before the\n" +
+			"        last run (falls back to resetting it if there's no\n" +
+			"        'prev.json.bak' to roll back to yet).\n\n" +
+			"Add patterns to 'watcher/.watchignore' (gitignore-style globs, one per\n" +
+			"line) to exclude paths from the scan. Pass --follow-symlinks to\n" +
+			"traverse symlinked directories instead of skipping them. Pass\n" +
+			"--store-url=<url> to persist snapshots to a remote HTTP store instead\n" +
+			"of 'watcher/prev.json'. Pass --hash=<algorithm> (sha256 or sha512) to\n" +
+			"choose the hash algorithm; it defaults to whatever the existing\n" +
+			"snapshot was built with, or sha256 for a new one.\n\n" +
+			"Each run writes 'watcher/changed_files.txt' (a flat list of affected\n" +
+			"paths) and 'watcher/changes.json' (the same changes as typed\n" +
+			"added/modified/deleted/renamed events) for downstream tools.\n\n" +
+			"Pass the 'watch' command instead of running once to start a\n" +
+			"long-running daemon that re-hashes files incrementally as it\n" +
+			"receives filesystem events. Pass --listen=unix:<path> or\n" +
+			"--listen=tcp:<host:port> to also stream each change as\n" +
+			"newline-delimited JSON to anyone connected to that address.",
 	)
 }
 
-func clearPrevJson() error {
-	err := os.WriteFile("watcher/prev.json", []byte("{}"), 0644)
-	if err != nil {
-		return err
+// clearPrevJson restores 'watcher/prev.json' from its '.bak' copy if one
+// exists, reporting true for a rollback. Otherwise it falls back to
+// resetting prev.json to an empty snapshot, as it did before backups
+// existed (e.g. on the very first run).
+func clearPrevJson() (rolledBack bool, err error) {
+	store := NewLocalStore(prevFilePath)
+	if err := store.Rollback(); err == nil {
+		return true, nil
 	}
-	return nil
+	return false, store.Save(&Snapshot{Algorithm: defaultHashAlgorithm, Files: make(FileHashes)})
 }
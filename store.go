@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Store persists the "last-seen" FileHashes snapshot and optionally keeps a
+// history of past snapshots. Swapping implementations lets teams share a
+// canonical snapshot (e.g. across CI machines) without touching the diff
+// logic in main.
+type Store interface {
+	// Load returns the most recently saved snapshot, or an empty Snapshot
+	// if none has been saved yet.
+	Load() (*Snapshot, error)
+	// Save persists curr as the new most-recent snapshot.
+	Save(curr *Snapshot) error
+	// History returns past snapshots, oldest first. Implementations that
+	// don't retain history may return a slice containing just the current
+	// snapshot.
+	History() ([]*Snapshot, error)
+}
+
+// LocalStore persists a single snapshot to a JSON file on disk. It's the
+// default Store and matches the tool's original prev.json behavior.
+type LocalStore struct {
+	Path string
+}
+
+// NewLocalStore returns a Store backed by the JSON file at path.
+func NewLocalStore(path string) *LocalStore {
+	return &LocalStore{Path: path}
+}
+
+func (s *LocalStore) Load() (*Snapshot, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Snapshot{Algorithm: defaultHashAlgorithm, Files: make(FileHashes)}, nil
+		}
+		return nil, fmt.Errorf("error reading hash file: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("error unmarshaling hash file: %w", err)
+	}
+	if snapshot.Files == nil {
+		snapshot.Files = make(FileHashes)
+	}
+	return &snapshot, nil
+}
+
+// Save atomically writes curr to s.Path, first preserving whatever was
+// there as s.Path+".bak" so Rollback can recover it.
+func (s *LocalStore) Save(curr *Snapshot) error {
+	data, err := json.MarshalIndent(curr, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling current hashes: %w", err)
+	}
+	if err := atomicSaveWithBackup(s.Path, data, 0644); err != nil {
+		return fmt.Errorf("%w.\nPlease run `watcher init` to generate necessary files", err)
+	}
+	return nil
+}
+
+// Rollback restores s.Path from the backup written by the previous Save,
+// turning `watcher clear` into a rollback rather than a wipe.
+func (s *LocalStore) Rollback() error {
+	return rollbackFromBackup(s.Path, 0644)
+}
+
+// History for LocalStore only ever has the current snapshot on disk; the
+// file format doesn't retain older versions.
+func (s *LocalStore) History() ([]*Snapshot, error) {
+	curr, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return []*Snapshot{curr}, nil
+}
+
+// HTTPStore persists snapshots to a remote endpoint via PUT/GET, following
+// the pattern of remote artifact caches. Save sends an X-Content-SHA256
+// header so the server can verify the payload wasn't corrupted in transit.
+type HTTPStore struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPStore returns a Store backed by the remote snapshot at baseURL.
+// GET baseURL loads the latest snapshot, PUT baseURL saves one, and GET
+// baseURL+"/history" lists past snapshots oldest first.
+func NewHTTPStore(baseURL string) *HTTPStore {
+	return &HTTPStore{BaseURL: baseURL, Client: http.DefaultClient}
+}
+
+func (s *HTTPStore) Load() (*Snapshot, error) {
+	resp, err := s.Client.Get(s.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return &Snapshot{Algorithm: defaultHashAlgorithm, Files: make(FileHashes)}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching snapshot: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot response: %w", err)
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot: %w", err)
+	}
+	if snapshot.Files == nil {
+		snapshot.Files = make(FileHashes)
+	}
+	return &snapshot, nil
+}
+
+func (s *HTTPStore) Save(curr *Snapshot) error {
+	data, err := json.Marshal(curr)
+	if err != nil {
+		return fmt.Errorf("error marshaling current hashes: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	req, err := http.NewRequest(http.MethodPut, s.BaseURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error building snapshot request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Content-SHA256", hex.EncodeToString(sum[:]))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error saving snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status saving snapshot: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *HTTPStore) History() ([]*Snapshot, error) {
+	resp, err := s.Client.Get(s.BaseURL + "/history")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching snapshot history: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching snapshot history: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot history response: %w", err)
+	}
+	var history []*Snapshot
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, fmt.Errorf("error unmarshaling snapshot history: %w", err)
+	}
+	return history, nil
+}
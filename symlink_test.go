@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestComputeHashesFollowsSymlinkedDir exercises --follow-symlinks against a
+// directory symlink pointing at a sibling directory with real content. It
+// guards against a regression where the nested WalkDir's first entry
+// (the symlink itself, re-Lstat'd as walkRoot) looped back into
+// handleSymlink and bailed out before ever reading the target's children.
+func TestComputeHashesFollowsSymlinkedDir(t *testing.T) {
+	root := t.TempDir()
+
+	realDir := filepath.Join(root, "real")
+	if err := os.Mkdir(realDir, 0755); err != nil {
+		t.Fatalf("error creating real dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(realDir, "file.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	linkDir := filepath.Join(root, "link")
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Skipf("symlinks unsupported on this platform: %v", err)
+	}
+
+	hasher, err := hasherFor(defaultHashAlgorithm)
+	if err != nil {
+		t.Fatalf("error resolving hasher: %v", err)
+	}
+
+	hashes := computeHashes(root, make(FileHashes), true, hasher)
+
+	wantPath := filepath.Join(linkDir, "file.txt")
+	if _, ok := hashes[wantPath]; !ok {
+		t.Fatalf("expected %s to be hashed via the followed symlink, got %v", wantPath, hashes)
+	}
+}
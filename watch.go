@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// debounceWindow is how long runWatch waits after the last fsnotify event
+// in a burst before recomputing and persisting the affected hashes.
+const debounceWindow = 200 * time.Millisecond
+
+// runWatch keeps the process alive, using fsnotify to receive OS-level
+// create/write/rename/remove events and re-hashing only the affected files
+// instead of re-walking the whole tree on every change. It loads prevHashes
+// once, then maintains it in memory, debouncing bursts of events before
+// atomically persisting the updated snapshot via store. If listenAddr is
+// non-empty, committed batches of Change events are also streamed as
+// newline-delimited JSON to anyone connected to that address.
+func runWatch(dir string, prevHashes FileHashes, followSymlinks bool, hasher Hasher, hashAlgorithm string, store Store, ignoreList *IgnoreList, listenAddr string) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("error creating fsnotify watcher: %w", err)
+	}
+	defer fsw.Close()
+
+	if err := addWatchedDirs(fsw, dir, ignoreList); err != nil {
+		return fmt.Errorf("error watching %s: %w", dir, err)
+	}
+
+	var subs subscribers
+	if listenAddr != "" {
+		ln, err := listenOn(listenAddr)
+		if err != nil {
+			return err
+		}
+		defer ln.Close()
+		go subs.serve(ln)
+		fmt.Printf("Streaming change events on %s\n", listenAddr)
+	}
+
+	var mu sync.Mutex
+	hashes := prevHashes
+	dirty := make(map[string]bool)
+	flush := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		paths := dirty
+		dirty = make(map[string]bool)
+		if len(paths) == 0 {
+			return
+		}
+		changes := applyDirtyPaths(paths, hashes, hasher)
+		if len(changes) == 0 {
+			return
+		}
+		if err := store.Save(&Snapshot{Algorithm: hashAlgorithm, Files: hashes}); err != nil {
+			log.Printf("Error saving snapshot: %v", err)
+		}
+		writeChangeReports(changes)
+		for _, change := range changes {
+			fmt.Printf("%s: %s\n", change.Kind, change.Path)
+		}
+		subs.broadcast(changes)
+	}
+
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			rel, relErr := filepath.Rel(dir, event.Name)
+			if relErr != nil {
+				rel = event.Name
+			}
+			if rel == watcherDirName || strings.HasPrefix(rel, watcherDirName+string(filepath.Separator)) {
+				continue
+			}
+			if ignoreList.Matches(rel, false) {
+				continue
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				if info, statErr := os.Stat(event.Name); statErr == nil && info.IsDir() {
+					if err := addWatchedDirs(fsw, event.Name, ignoreList); err != nil {
+						log.Printf("Error watching new directory %s: %v", event.Name, err)
+					}
+				}
+			}
+			mu.Lock()
+			dirty[event.Name] = true
+			mu.Unlock()
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(debounceWindow, flush)
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("fsnotify error: %v", err)
+		}
+	}
+}
+
+// addWatchedDirs registers dir and every non-ignored subdirectory under it
+// with fsw, mirroring the skip rules computeHashes applies during a normal
+// scan (fsnotify watches must be added per-directory; it doesn't recurse).
+func addWatchedDirs(fsw *fsnotify.Watcher, dir string, ignoreList *IgnoreList) error {
+	return filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(dir, p)
+		if relErr != nil {
+			rel = p
+		}
+		if p != dir && (rel == watcherDirName || ignoreList.Matches(rel, true)) {
+			return filepath.SkipDir
+		}
+		return fsw.Add(p)
+	})
+}
+
+// applyDirtyPaths re-stats and, if needed, rehashes each path touched since
+// the last flush, mutating hashes in place and returning the resulting
+// Change events. Added and deleted paths within the same batch are paired
+// by hash into Renamed changes via pairRenames, the same way diffChanges
+// pairs them across a full tree comparison, so a move shows up as one
+// Renamed event to stream subscribers rather than an unrelated Deleted and
+// Added pair.
+func applyDirtyPaths(paths map[string]bool, hashes FileHashes, hasher Hasher) []Change {
+	var changes []Change
+	added := make(map[string]FileEntry)
+	deleted := make(map[string]FileEntry)
+	for path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if prev, ok := hashes[path]; ok {
+					delete(hashes, path)
+					deleted[path] = prev
+				}
+				continue
+			}
+			log.Printf("Error stating %s: %v", path, err)
+			continue
+		}
+		if info.IsDir() {
+			continue
+		}
+		prev, existed := hashes[path]
+		if existed && prev.unchanged(info, hasher) {
+			continue
+		}
+		digest, err := hashFile(path, hasher)
+		if err != nil {
+			log.Printf("Error hashing %s: %v", path, err)
+			continue
+		}
+		entry := FileEntry{Hash: digest, ModTime: info.ModTime(), Size: info.Size()}
+		hashes[path] = entry
+		if existed {
+			changes = append(changes, Change{Kind: Modified, Path: path, Hash: digest})
+		} else {
+			added[path] = entry
+		}
+	}
+	changes = append(changes, pairRenames(added, deleted)...)
+	return changes
+}
+
+// subscribers fans committed Change batches out to every connection opened
+// against the watch daemon's listen address, each as newline-delimited
+// JSON.
+type subscribers struct {
+	mu  sync.Mutex
+	chs []chan []Change
+}
+
+func (s *subscribers) serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		ch := make(chan []Change, 16)
+		s.mu.Lock()
+		s.chs = append(s.chs, ch)
+		s.mu.Unlock()
+		go s.stream(conn, ch)
+	}
+}
+
+func (s *subscribers) stream(conn net.Conn, ch chan []Change) {
+	defer conn.Close()
+	w := bufio.NewWriter(conn)
+	for changes := range ch {
+		for _, change := range changes {
+			data, err := json.Marshal(change)
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write(append(data, '\n')); err != nil {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *subscribers) broadcast(changes []Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.chs {
+		select {
+		case ch <- changes:
+		default:
+			// Slow subscriber; drop this batch rather than block the watcher.
+		}
+	}
+}
+
+// listenOn creates a net.Listener from an address of the form
+// "unix:/path/to.sock" or "tcp:host:port".
+func listenOn(addr string) (net.Listener, error) {
+	network, address, ok := strings.Cut(addr, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid --listen address %q, expected unix:<path> or tcp:<host:port>", addr)
+	}
+	switch network {
+	case "unix":
+		os.Remove(address)
+		return net.Listen("unix", address)
+	case "tcp":
+		return net.Listen("tcp", address)
+	default:
+		return nil, fmt.Errorf("unsupported --listen network %q, expected unix or tcp", network)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mustHasher(t *testing.T) Hasher {
+	t.Helper()
+	hasher, err := hasherFor(defaultHashAlgorithm)
+	if err != nil {
+		t.Fatalf("error resolving hasher: %v", err)
+	}
+	return hasher
+}
+
+func TestApplyDirtyPathsClassifiesChanges(t *testing.T) {
+	dir := t.TempDir()
+	hasher := mustHasher(t)
+
+	unchangedPath := filepath.Join(dir, "unchanged.txt")
+	modifiedPath := filepath.Join(dir, "modified.txt")
+	deletedPath := filepath.Join(dir, "deleted.txt")
+	addedPath := filepath.Join(dir, "added.txt")
+
+	for _, f := range []string{unchangedPath, modifiedPath, deletedPath} {
+		if err := os.WriteFile(f, []byte("original"), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", f, err)
+		}
+	}
+
+	hashes := make(FileHashes)
+	for _, f := range []string{unchangedPath, modifiedPath, deletedPath} {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatalf("error stating %s: %v", f, err)
+		}
+		digest, err := hashFile(f, hasher)
+		if err != nil {
+			t.Fatalf("error hashing %s: %v", f, err)
+		}
+		hashes[f] = FileEntry{Hash: digest, ModTime: info.ModTime(), Size: info.Size()}
+	}
+
+	// Give the filesystem's mtime resolution room to show modifiedPath as
+	// genuinely changed rather than coincidentally unchanged.
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(modifiedPath, []byte("changed"), 0644); err != nil {
+		t.Fatalf("error rewriting %s: %v", modifiedPath, err)
+	}
+	if err := os.WriteFile(addedPath, []byte("new"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", addedPath, err)
+	}
+	if err := os.Remove(deletedPath); err != nil {
+		t.Fatalf("error removing %s: %v", deletedPath, err)
+	}
+
+	dirty := map[string]bool{
+		unchangedPath: true,
+		modifiedPath:  true,
+		deletedPath:   true,
+		addedPath:     true,
+	}
+	changes := applyDirtyPaths(dirty, hashes, hasher)
+
+	byPath := make(map[string]Change)
+	for _, c := range changes {
+		byPath[c.Path] = c
+	}
+	if len(changes) != 3 {
+		t.Fatalf("applyDirtyPaths() = %+v, want exactly 3 changes (no change reported for the unchanged path)", changes)
+	}
+	if _, ok := byPath[unchangedPath]; ok {
+		t.Errorf("unchanged path %s unexpectedly reported a change", unchangedPath)
+	}
+	if c, ok := byPath[modifiedPath]; !ok || c.Kind != Modified {
+		t.Errorf("modified path = %+v, want Kind=Modified", c)
+	}
+	if c, ok := byPath[addedPath]; !ok || c.Kind != Added {
+		t.Errorf("added path = %+v, want Kind=Added", c)
+	}
+	if c, ok := byPath[deletedPath]; !ok || c.Kind != Deleted {
+		t.Errorf("deleted path = %+v, want Kind=Deleted", c)
+	}
+	if _, stillPresent := hashes[deletedPath]; stillPresent {
+		t.Errorf("expected deleted path to be removed from hashes")
+	}
+	if _, present := hashes[addedPath]; !present {
+		t.Errorf("expected added path to be recorded in hashes")
+	}
+}
+
+// TestApplyDirtyPathsPairsRenameAcrossBatch ensures a move within a single
+// flush batch is reported as one Renamed change, matching how diffChanges
+// reports the same move for a non-daemon run, rather than an unrelated
+// Deleted/Added pair.
+func TestApplyDirtyPathsPairsRenameAcrossBatch(t *testing.T) {
+	dir := t.TempDir()
+	hasher := mustHasher(t)
+
+	oldPath := filepath.Join(dir, "old.txt")
+	newPath := filepath.Join(dir, "new.txt")
+	if err := os.WriteFile(oldPath, []byte("same contents"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", oldPath, err)
+	}
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		t.Fatalf("error stating %s: %v", oldPath, err)
+	}
+	digest, err := hashFile(oldPath, hasher)
+	if err != nil {
+		t.Fatalf("error hashing %s: %v", oldPath, err)
+	}
+	hashes := FileHashes{oldPath: {Hash: digest, ModTime: info.ModTime(), Size: info.Size()}}
+
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatalf("error renaming: %v", err)
+	}
+
+	changes := applyDirtyPaths(map[string]bool{oldPath: true, newPath: true}, hashes, hasher)
+	if len(changes) != 1 {
+		t.Fatalf("applyDirtyPaths() = %+v, want exactly one Renamed change", changes)
+	}
+	want := Change{Kind: Renamed, Path: newPath, OldPath: oldPath, Hash: digest}
+	if changes[0] != want {
+		t.Errorf("applyDirtyPaths()[0] = %+v, want %+v", changes[0], want)
+	}
+}
+
+func waitForSubscriber(t *testing.T, subs *subscribers) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		subs.mu.Lock()
+		n := len(subs.chs)
+		subs.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out waiting for a subscriber connection")
+}
+
+func TestSubscribersBroadcastStreamsNDJSON(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	defer ln.Close()
+
+	var subs subscribers
+	go subs.serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("error dialing: %v", err)
+	}
+	defer conn.Close()
+
+	waitForSubscriber(t, &subs)
+
+	want := []Change{{Kind: Modified, Path: "a.txt", Hash: "sha256:aaa"}}
+	subs.broadcast(want)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	scanner := bufio.NewScanner(conn)
+	if !scanner.Scan() {
+		t.Fatalf("expected a line from the subscriber stream, got err = %v", scanner.Err())
+	}
+	var got Change
+	if err := json.Unmarshal(scanner.Bytes(), &got); err != nil {
+		t.Fatalf("error unmarshaling streamed change: %v", err)
+	}
+	if got != want[0] {
+		t.Errorf("streamed change = %+v, want %+v", got, want[0])
+	}
+}
+
+func TestListenOnUnixAndTCP(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "watcher.sock")
+	unixLn, err := listenOn(fmt.Sprintf("unix:%s", sockPath))
+	if err != nil {
+		t.Fatalf("listenOn(unix) error = %v", err)
+	}
+	defer unixLn.Close()
+	if unixLn.Addr().Network() != "unix" {
+		t.Errorf("unix listener network = %q, want unix", unixLn.Addr().Network())
+	}
+
+	tcpLn, err := listenOn("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listenOn(tcp) error = %v", err)
+	}
+	defer tcpLn.Close()
+	if tcpLn.Addr().Network() != "tcp" {
+		t.Errorf("tcp listener network = %q, want tcp", tcpLn.Addr().Network())
+	}
+}
+
+func TestListenOnRejectsUnknownNetwork(t *testing.T) {
+	if _, err := listenOn("carrier-pigeon:nowhere"); err == nil {
+		t.Fatalf("expected an error for an unsupported network")
+	}
+	if _, err := listenOn("no-colon-here"); err == nil {
+		t.Fatalf("expected an error for a malformed address")
+	}
+}
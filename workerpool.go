@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// hashQueueSize bounds how many pending paths can sit in the job channel at
+// once, so a very large tree doesn't buffer every path in memory at the
+// same time.
+const hashQueueSize = 256
+
+// hashResult is what a worker reports back for a single path.
+type hashResult struct {
+	path  string
+	entry FileEntry
+	err   error
+}
+
+// hashFilesParallel hashes paths using a pool of runtime.NumCPU() workers,
+// feeding paths through a bounded channel and collecting results on
+// another. Each worker stats and hashes independently, so there's no shared
+// map to guard until results are merged into the map this function returns.
+func hashFilesParallel(paths []string, hasher Hasher) FileHashes {
+	hashes := make(FileHashes, len(paths))
+	if len(paths) == 0 {
+		return hashes
+	}
+
+	numWorkers := runtime.NumCPU()
+	if numWorkers > len(paths) {
+		numWorkers = len(paths)
+	}
+	jobs := make(chan string, hashQueueSize)
+	results := make(chan hashResult, hashQueueSize)
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				results <- hashOne(path, hasher)
+			}
+		}()
+	}
+	go func() {
+		for _, path := range paths {
+			jobs <- path
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			log.Printf("Error hashing file %s: %v", r.path, r.err)
+			continue
+		}
+		hashes[r.path] = r.entry
+	}
+	return hashes
+}
+
+// hashOne stats and hashes a single path, returning its result for
+// hashFilesParallel to merge in.
+func hashOne(path string, hasher Hasher) hashResult {
+	info, err := os.Stat(path)
+	if err != nil {
+		return hashResult{path: path, err: err}
+	}
+	digest, err := hashFile(path, hasher)
+	if err != nil {
+		return hashResult{path: path, err: err}
+	}
+	return hashResult{path: path, entry: FileEntry{Hash: digest, ModTime: info.ModTime(), Size: info.Size()}}
+}
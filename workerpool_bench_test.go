@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchTreeSizes mirrors the gopls approach of running the same benchmark
+// across several representative directory sizes, so a throughput
+// regression in hashFilesParallel shows up at the size where it matters.
+var benchTreeSizes = []int{10, 100, 1000}
+
+// makeBenchTree creates n small files under a fresh temp directory and
+// returns their paths.
+func makeBenchTree(b *testing.B, n int) []string {
+	b.Helper()
+	dir := b.TempDir()
+	paths := make([]string, n)
+	for i := 0; i < n; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("contents of file %d", i)), 0644); err != nil {
+			b.Fatalf("error writing benchmark file: %v", err)
+		}
+		paths[i] = path
+	}
+	return paths
+}
+
+func BenchmarkHashFilesParallel(b *testing.B) {
+	hasher, err := hasherFor(defaultHashAlgorithm)
+	if err != nil {
+		b.Fatalf("error resolving hasher: %v", err)
+	}
+	for _, n := range benchTreeSizes {
+		paths := makeBenchTree(b, n)
+		b.Run(fmt.Sprintf("files=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				hashFilesParallel(paths, hasher)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHashFilesParallelReturnsCorrectEntries(t *testing.T) {
+	dir := t.TempDir()
+	hasher := mustHasher(t)
+
+	contents := map[string]string{
+		"a.txt": "hello",
+		"b.txt": "world",
+	}
+	var paths []string
+	wantDigest := make(map[string]string)
+	for name, content := range contents {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("error writing %s: %v", path, err)
+		}
+		digest, err := hashFile(path, hasher)
+		if err != nil {
+			t.Fatalf("error hashing %s: %v", path, err)
+		}
+		wantDigest[path] = digest
+		paths = append(paths, path)
+	}
+
+	got := hashFilesParallel(paths, hasher)
+	if len(got) != len(paths) {
+		t.Fatalf("hashFilesParallel() returned %d entries, want %d", len(got), len(paths))
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("error stating %s: %v", path, err)
+		}
+		entry, ok := got[path]
+		if !ok {
+			t.Fatalf("missing entry for %s", path)
+		}
+		if entry.Hash != wantDigest[path] {
+			t.Errorf("entry[%s].Hash = %q, want %q", path, entry.Hash, wantDigest[path])
+		}
+		if entry.Size != info.Size() {
+			t.Errorf("entry[%s].Size = %d, want %d", path, entry.Size, info.Size())
+		}
+		if !entry.ModTime.Equal(info.ModTime()) {
+			t.Errorf("entry[%s].ModTime = %v, want %v", path, entry.ModTime, info.ModTime())
+		}
+	}
+}
+
+func TestHashFilesParallelDropsFailedPathsWithoutCorruptingMap(t *testing.T) {
+	dir := t.TempDir()
+	hasher := mustHasher(t)
+
+	goodPath := filepath.Join(dir, "good.txt")
+	if err := os.WriteFile(goodPath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", goodPath, err)
+	}
+	missingPath := filepath.Join(dir, "does-not-exist.txt")
+
+	got := hashFilesParallel([]string{goodPath, missingPath}, hasher)
+	if len(got) != 1 {
+		t.Fatalf("hashFilesParallel() = %v, want exactly the good path", got)
+	}
+	if _, ok := got[goodPath]; !ok {
+		t.Errorf("missing entry for %s", goodPath)
+	}
+	if _, ok := got[missingPath]; ok {
+		t.Errorf("unexpected entry for path that never existed: %s", missingPath)
+	}
+}
+
+func TestHashFilesParallelEmptyInput(t *testing.T) {
+	hasher := mustHasher(t)
+	got := hashFilesParallel(nil, hasher)
+	if len(got) != 0 {
+		t.Errorf("hashFilesParallel(nil) = %v, want empty", got)
+	}
+}
+
+// TestHashFilesParallelClampsWorkerCountToPathCount exercises the
+// numWorkers > len(paths) clamp with a path count smaller than
+// runtime.NumCPU() on the test machine; it only asserts on the outcome
+// since worker count isn't directly observable, but a clamp bug (e.g. an
+// unbuffered jobs channel sized to the wrong worker count) would deadlock
+// or drop results here rather than returning every path's entry.
+func TestHashFilesParallelClampsWorkerCountToPathCount(t *testing.T) {
+	dir := t.TempDir()
+	hasher := mustHasher(t)
+
+	path := filepath.Join(dir, "only.txt")
+	if err := os.WriteFile(path, []byte("solo"), 0644); err != nil {
+		t.Fatalf("error writing %s: %v", path, err)
+	}
+
+	got := hashFilesParallel([]string{path}, hasher)
+	if len(got) != 1 {
+		t.Fatalf("hashFilesParallel() = %v, want exactly one entry", got)
+	}
+	if _, ok := got[path]; !ok {
+		t.Errorf("missing entry for %s", path)
+	}
+}